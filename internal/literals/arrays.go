@@ -0,0 +1,235 @@
+package literals
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// numericElemSize returns the number of bytes used to encode a single
+// element when packing a numeric composite literal into a byte payload,
+// or 0 if kind isn't a fixed-width integer we know how to pack.
+func numericElemSize(kind types.BasicKind) int {
+	switch kind {
+	case types.Uint8, types.Int8:
+		return 1
+	case types.Uint16, types.Int16:
+		return 2
+	case types.Uint32, types.Int32:
+		return 4
+	case types.Uint64, types.Int64, types.Uint, types.Int:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// obfuscateNumericArrayLit obfuscates a dense (no KeyValueExpr) array or
+// slice literal of integers or bools by packing every element into a
+// single []byte payload, running it through the usual obfuscator
+// pipeline, and emitting a lambda that unpacks it back into the original
+// element type. arrayLen is the array length, or -1 for a slice.
+//
+// KeyValueExpr elements (sparse literals) are left alone: their Value
+// sub-expressions are already obfuscated individually by Obfuscate's
+// generic *ast.BasicLit/*ast.Ident handling, so there's nothing extra to
+// do here and returning false lets the literal pass through unchanged.
+func obfuscateNumericArrayLit(opts *Options, fctx *fileContext, x *ast.CompositeLit, elem types.Type, arrayLen int64) (ast.Expr, bool) {
+	basic, ok := elem.Underlying().(*types.Basic)
+	if !ok {
+		return nil, false
+	}
+
+	// elemTypeName can only rebuild a bare, same-package identifier for a
+	// named element type (e.g. Flags, not otherpkg.Flags); a qualified
+	// element type in the source means the type came from another
+	// package, and rebuilding just its bare name would either fail to
+	// resolve or silently bind to an unrelated local type sharing that
+	// name. Bail out the same way as for an unsupported underlying type.
+	if arrType, ok := x.Type.(*ast.ArrayType); ok {
+		if _, qualified := arrType.Elt.(*ast.SelectorExpr); qualified {
+			return nil, false
+		}
+	}
+
+	isBool := basic.Kind() == types.Bool
+	elemSize := 1
+	if !isBool {
+		elemSize = numericElemSize(basic.Kind())
+		if elemSize == 0 {
+			return nil, false
+		}
+	}
+
+	data, ok := packNumericElements(x.Elts, basic.Kind(), elemSize)
+	if !ok {
+		return nil, false
+	}
+	// Elements an array literal leaves out are zero, and the decoder reads
+	// one element per array slot, so pad the payload to the full length.
+	if arrayLen >= 0 {
+		if full := int(arrayLen) * elemSize; len(data) < full {
+			data = append(data, make([]byte, full-len(data))...)
+		}
+	}
+	if !opts.shouldObfuscate(len(data)) {
+		return nil, false
+	}
+
+	return obfuscateNumericArray(opts, fctx, data, elemSize, basic.Kind(), elemTypeName(elem, basic), arrayLen), true
+}
+
+// elemTypeName returns the name to use for the decoded array or slice's
+// element type. For a named type (e.g. `type Flags uint32`) this is its
+// declared name, not the underlying basic type: resynthesizing from e.g.
+// "uint32" would make the decoded lambda return a plain
+// []uint32/[N]uint32, which isn't assignable back into the original
+// []Flags/[N]Flags composite literal's type.
+func elemTypeName(elem types.Type, basic *types.Basic) string {
+	if named, ok := elem.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return basic.Name()
+}
+
+// packNumericElements flattens a dense composite literal's elements into
+// their raw little-endian byte representation. It returns ok == false if
+// any element isn't a plain constant (e.g. a KeyValueExpr, meaning the
+// literal is sparse, or an identifier/call we can't evaluate statically).
+func packNumericElements(elts []ast.Expr, kind types.BasicKind, elemSize int) ([]byte, bool) {
+	data := make([]byte, 0, len(elts)*elemSize)
+
+	for _, el := range elts {
+		var value uint64
+
+		if kind == types.Bool {
+			id, ok := el.(*ast.Ident)
+			if !ok {
+				return nil, false
+			}
+			switch id.Name {
+			case "true":
+				value = 1
+			case "false":
+				value = 0
+			default:
+				return nil, false
+			}
+		} else {
+			lit := el
+			neg := false
+			if u, ok := lit.(*ast.UnaryExpr); ok && u.Op == token.SUB {
+				neg = true
+				lit = u.X
+			}
+
+			bl, ok := lit.(*ast.BasicLit)
+			if !ok || bl.Kind != token.INT {
+				return nil, false
+			}
+
+			n, err := strconv.ParseUint(bl.Value, 0, elemSize*8)
+			if err != nil {
+				s, serr := strconv.ParseInt(bl.Value, 0, elemSize*8)
+				if serr != nil {
+					return nil, false
+				}
+				n = uint64(s)
+			}
+			if neg {
+				n = uint64(-int64(n))
+			}
+			value = n
+		}
+
+		buf := make([]byte, elemSize)
+		for i := 0; i < elemSize; i++ {
+			buf[i] = byte(value >> (8 * i))
+		}
+		data = append(data, buf...)
+	}
+
+	return data, true
+}
+
+// obfuscateNumericArray is the numeric/bool counterpart of
+// obfuscateByteArray/obfuscateByteSlice: it obfuscates the packed byte
+// payload and wraps it in a lambda that reassembles the typed array or
+// slice one element at a time.
+func obfuscateNumericArray(opts *Options, fctx *fileContext, data []byte, elemSize int, elemKind types.BasicKind, elemName string, arrayLen int64) *ast.CallExpr {
+	obfuscator := opts.randObfuscator(len(data))
+	block := obfuscator.obfuscate(data)
+
+	count := strconv.Itoa(len(data) / elemSize)
+
+	var resultType ast.Expr = &ast.ArrayType{Elt: ident(elemName)}
+	var initdata ast.Stmt
+	if arrayLen >= 0 {
+		resultType = &ast.ArrayType{Len: intLiteral(strconv.FormatInt(arrayLen, 10)), Elt: ident(elemName)}
+		initdata = &ast.DeclStmt{Decl: &ast.GenDecl{
+			Tok:   token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{ident("newdata")}, Type: resultType}},
+		}}
+	} else {
+		initdata = &ast.AssignStmt{
+			Lhs: []ast.Expr{ident("newdata")},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{callExpr(ident("make"), resultType, intLiteral(count))},
+		}
+	}
+
+	unpack := []ast.Stmt{
+		initdata,
+		&ast.RangeStmt{
+			Key: ident("i"),
+			Tok: token.DEFINE,
+			X:   ident("newdata"),
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{indexExpr("newdata", ident("i"))},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{decodeNumericElem(ident("i"), elemSize, elemKind, elemName)},
+				},
+			}},
+		},
+		returnStmt(ident("newdata")),
+	}
+	block.List = append(block.List, unpack...)
+
+	var sliceType ast.Expr
+	if arrayLen < 0 {
+		sliceType = resultType
+	}
+	return fctx.hoist(len(data), lambdaCall(resultType, block), sliceType)
+}
+
+// decodeNumericElem builds the expression that reconstructs element idx
+// of the packed payload, the inverse of packNumericElements.
+func decodeNumericElem(idx ast.Expr, elemSize int, elemKind types.BasicKind, elemName string) ast.Expr {
+	base := ast.Expr(idx)
+	if elemSize > 1 {
+		base = &ast.BinaryExpr{X: idx, Op: token.MUL, Y: intLiteral(strconv.Itoa(elemSize))}
+	}
+
+	if elemKind == types.Bool {
+		return callExpr(ident(elemName), &ast.BinaryExpr{X: indexExpr("data", base), Op: token.EQL, Y: intLiteral("1")})
+	}
+
+	if elemSize == 1 {
+		return callExpr(ident(elemName), indexExpr("data", base))
+	}
+
+	uintName := "uint" + strconv.Itoa(elemSize*8)
+	var expr ast.Expr = callExpr(ident(uintName), indexExpr("data", base))
+	for i := 1; i < elemSize; i++ {
+		byteExpr := callExpr(ident(uintName), indexExpr("data", &ast.BinaryExpr{
+			X:  base,
+			Op: token.ADD,
+			Y:  intLiteral(strconv.Itoa(i)),
+		}))
+		shifted := &ast.BinaryExpr{X: byteExpr, Op: token.SHL, Y: intLiteral(strconv.Itoa(8 * i))}
+		expr = &ast.BinaryExpr{X: expr, Op: token.OR, Y: shifted}
+	}
+	return callExpr(ident(elemName), expr)
+}
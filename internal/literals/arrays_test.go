@@ -0,0 +1,142 @@
+package literals
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"go/types"
+	mathrand "math/rand"
+	"testing"
+)
+
+func intLit(v string) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.INT, Value: v}
+}
+
+func negLit(v string) *ast.UnaryExpr {
+	return &ast.UnaryExpr{Op: token.SUB, X: intLit(v)}
+}
+
+func boolIdent(v string) *ast.Ident {
+	return &ast.Ident{Name: v}
+}
+
+func TestPackNumericElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		elts    []ast.Expr
+		kind    types.BasicKind
+		size    int
+		want    []byte
+		wantOK  bool
+	}{
+		{
+			name:   "uint8",
+			elts:   []ast.Expr{intLit("1"), intLit("2"), intLit("255")},
+			kind:   types.Uint8,
+			size:   1,
+			want:   []byte{1, 2, 255},
+			wantOK: true,
+		},
+		{
+			name:   "int32 negative little-endian",
+			elts:   []ast.Expr{negLit("1")},
+			kind:   types.Int32,
+			size:   4,
+			want:   []byte{0xff, 0xff, 0xff, 0xff},
+			wantOK: true,
+		},
+		{
+			name:   "bool",
+			elts:   []ast.Expr{boolIdent("true"), boolIdent("false")},
+			kind:   types.Bool,
+			size:   1,
+			want:   []byte{1, 0},
+			wantOK: true,
+		},
+		{
+			name: "sparse literal falls back",
+			elts: []ast.Expr{&ast.KeyValueExpr{
+				Key:   intLit("2"),
+				Value: intLit("9"),
+			}},
+			kind:   types.Int,
+			size:   8,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := packNumericElements(tt.elts, tt.kind, tt.size)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !bytes.Equal(got, tt.want) {
+				t.Fatalf("packed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObfuscateNumericArrayLitNamedType(t *testing.T) {
+	opts := &Options{rand: mathrand.New(mathrand.NewSource(1)), pool: obfuscators, minSize: 1, maxSize: defaultMaxSize}
+	elts := []ast.Expr{intLit("1"), intLit("2"), intLit("3")}
+
+	t.Run("same package named type is rebuilt by name", func(t *testing.T) {
+		fctx := newFileContext(opts, &ast.File{}, new(int))
+		pkg := types.NewPackage("example.com/thispkg", "literals")
+		named := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Flags", nil), types.Typ[types.Uint32], nil)
+		x := &ast.CompositeLit{
+			Type: &ast.ArrayType{Len: intLit("3"), Elt: ident("Flags")},
+			Elts: elts,
+		}
+
+		expr, ok := obfuscateNumericArrayLit(opts, fctx, x, named, 3)
+		if !ok {
+			t.Fatal("obfuscateNumericArrayLit refused a same-package named type")
+		}
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			t.Fatalf("expr = %#v, want *ast.CallExpr", expr)
+		}
+		fn, ok := call.Fun.(*ast.FuncLit)
+		if !ok {
+			t.Fatalf("call.Fun = %#v, want *ast.FuncLit", call.Fun)
+		}
+		resultElt := fn.Type.Results.List[0].Type.(*ast.ArrayType).Elt.(*ast.Ident).Name
+		if resultElt != "Flags" {
+			t.Fatalf("result element type = %q, want %q", resultElt, "Flags")
+		}
+	})
+
+	t.Run("imported named type is left alone", func(t *testing.T) {
+		fctx := newFileContext(opts, &ast.File{}, new(int))
+		pkg := types.NewPackage("example.com/otherpkg", "otherpkg")
+		named := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Flags", nil), types.Typ[types.Uint32], nil)
+		x := &ast.CompositeLit{
+			Type: &ast.ArrayType{Len: intLit("3"), Elt: &ast.SelectorExpr{X: ident("otherpkg"), Sel: ident("Flags")}},
+			Elts: elts,
+		}
+
+		if _, ok := obfuscateNumericArrayLit(opts, fctx, x, named, 3); ok {
+			t.Fatal("obfuscateNumericArrayLit rebuilt a qualified, cross-package element type instead of bailing out")
+		}
+	})
+}
+
+func TestSplitIntoChunksConcatenation(t *testing.T) {
+	data := []byte("abcdefghij0123456789")
+
+	for i := 0; i < 20; i++ {
+		chunks := splitIntoChunks(data)
+
+		var joined []byte
+		for _, c := range chunks {
+			joined = append(joined, c...)
+		}
+		if !bytes.Equal(joined, data) {
+			t.Fatalf("chunks %v don't reassemble to %v", chunks, data)
+		}
+	}
+}
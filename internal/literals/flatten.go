@@ -0,0 +1,157 @@
+package literals
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	mathrand "math/rand"
+	"sort"
+	"strconv"
+)
+
+// minChunks and maxChunks bound how many pieces flatten splits a payload
+// into: below minChunks the state machine adds overhead without adding
+// much confusion, and above maxChunks compile time for small payloads
+// starts to dominate.
+const (
+	minChunks = 2
+	maxChunks = 6
+)
+
+// flatten is an obfuscator that decodes its payload through a
+// control-flow-flattened state machine instead of the straight-line
+// transforms the other obfuscators use: the payload is split into
+// randomly sized chunks, each chunk is decoded with its own per-byte key
+// schedule, and a `for { switch state { ... } }` loop decodes one chunk
+// per case before jumping to the next state, itself looked up from a
+// transition table that is just as XOR-obfuscated as the payload.
+// Recovering the plaintext statically means reconstructing the whole
+// state machine instead of just reading a single basic block.
+type flatten struct{}
+
+func (flatten) obfuscate(data []byte) *ast.BlockStmt {
+	chunks := splitIntoChunks(data)
+
+	// ids[i] is the state that decodes chunks[i]; the extra, final entry
+	// is the terminal state, which has no chunk of its own. Shuffling
+	// them means the state numbers don't reveal chunk order.
+	ids := mathrand.Perm(len(chunks) + 1)
+	terminal := ids[len(chunks)]
+
+	const loopLabel = "flattenLoop"
+	intType := intTypes[types.Typ[types.Uint8]]
+
+	// xorObfuscated builds `cipher ^ <obfuscated key>`, which evaluates to
+	// plain at runtime but never puts the plaintext byte or its key in
+	// the binary as a bare constant.
+	xorObfuscated := func(plain byte) ast.Expr {
+		key := byte(mathrand.Intn(256))
+		return &ast.BinaryExpr{
+			X:  intLiteral(strconv.Itoa(int(plain ^ key))),
+			Op: token.XOR,
+			Y:  genObfuscateInt(uint64(key), intType),
+		}
+	}
+
+	// The transition table itself is obfuscated the same way the payload
+	// is: transitions[i] only evaluates to the next state for chunk i
+	// once the XOR with its obfuscated key runs.
+	transitionElts := make([]ast.Expr, len(chunks))
+	for i := range chunks {
+		next := terminal
+		if i+1 < len(chunks) {
+			next = ids[i+1]
+		}
+		transitionElts[i] = xorObfuscated(byte(next))
+	}
+
+	block := &ast.BlockStmt{
+		List: []ast.Stmt{
+			&ast.DeclStmt{Decl: &ast.GenDecl{
+				Tok: token.VAR,
+				Specs: []ast.Spec{&ast.ValueSpec{
+					Names: []*ast.Ident{ident("data")},
+					Type:  &ast.ArrayType{Elt: ident("byte")},
+				}},
+			}},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ident("transitions")},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.CompositeLit{
+					Type: &ast.ArrayType{Elt: ident("byte")},
+					Elts: transitionElts,
+				}},
+			},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ident("state")},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{intLiteral(strconv.Itoa(ids[0]))},
+			},
+		},
+	}
+
+	sw := &ast.SwitchStmt{Tag: ident("state"), Body: &ast.BlockStmt{}}
+
+	for i, chunk := range chunks {
+		// Every byte of the chunk gets its own randomly generated key, a
+		// full key schedule rather than one key reused for the chunk.
+		appendArgs := []ast.Expr{ident("data")}
+		for _, b := range chunk {
+			appendArgs = append(appendArgs, xorObfuscated(b))
+		}
+
+		caseBody := []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ident("data")},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{callExpr(ident("append"), appendArgs...)},
+			},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ident("state")},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{callExpr(ident("int"), indexExpr("transitions", intLiteral(strconv.Itoa(i))))},
+			},
+		}
+
+		sw.Body.List = append(sw.Body.List, &ast.CaseClause{
+			List: []ast.Expr{intLiteral(strconv.Itoa(ids[i]))},
+			Body: caseBody,
+		})
+	}
+
+	sw.Body.List = append(sw.Body.List, &ast.CaseClause{
+		List: []ast.Expr{intLiteral(strconv.Itoa(terminal))},
+		Body: []ast.Stmt{&ast.BranchStmt{Tok: token.BREAK, Label: ident(loopLabel)}},
+	})
+
+	block.List = append(block.List, &ast.LabeledStmt{
+		Label: ident(loopLabel),
+		Stmt:  &ast.ForStmt{Body: &ast.BlockStmt{List: []ast.Stmt{sw}}},
+	})
+
+	return block
+}
+
+// splitIntoChunks breaks data into a random number of contiguous,
+// randomly sized pieces, used as the state machine's decode units.
+func splitIntoChunks(data []byte) [][]byte {
+	if len(data) <= 1 {
+		return [][]byte{data}
+	}
+
+	n := minChunks + mathrand.Intn(maxChunks-minChunks+1)
+	if n > len(data) {
+		n = len(data)
+	}
+
+	cuts := mathrand.Perm(len(data) - 1)[:n-1]
+	sort.Ints(cuts)
+
+	chunks := make([][]byte, 0, n)
+	start := 0
+	for _, cut := range cuts {
+		chunks = append(chunks, data[start:cut+1])
+		start = cut + 1
+	}
+	return append(chunks, data[start:])
+}
@@ -0,0 +1,46 @@
+package literals
+
+import (
+	"go/ast"
+	"testing"
+)
+
+// TestFlattenObfuscateShape checks the structural invariants of the
+// generated state machine without needing to execute it: one case per
+// chunk plus the terminal case, and a transitions table with one entry
+// per chunk.
+func TestFlattenObfuscateShape(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	chunks := splitIntoChunks(data)
+
+	block := flatten{}.obfuscate(data)
+
+	var transitions *ast.CompositeLit
+	var sw *ast.SwitchStmt
+	for _, stmt := range block.List {
+		if assign, ok := stmt.(*ast.AssignStmt); ok {
+			if id, ok := assign.Lhs[0].(*ast.Ident); ok && id.Name == "transitions" {
+				transitions, _ = assign.Rhs[0].(*ast.CompositeLit)
+			}
+		}
+		if labeled, ok := stmt.(*ast.LabeledStmt); ok {
+			if loop, ok := labeled.Stmt.(*ast.ForStmt); ok {
+				sw, _ = loop.Body.List[0].(*ast.SwitchStmt)
+			}
+		}
+	}
+
+	if transitions == nil {
+		t.Fatal("obfuscate produced no transitions table")
+	}
+	if len(transitions.Elts) != len(chunks) {
+		t.Fatalf("transitions has %d entries, want %d (one per chunk)", len(transitions.Elts), len(chunks))
+	}
+
+	if sw == nil {
+		t.Fatal("obfuscate produced no state machine switch")
+	}
+	if len(sw.Body.List) != len(chunks)+1 {
+		t.Fatalf("switch has %d cases, want %d (one per chunk plus terminal)", len(sw.Body.List), len(chunks)+1)
+	}
+}
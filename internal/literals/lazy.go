@@ -0,0 +1,144 @@
+package literals
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// defaultLazyMinSize is the smallest literal, in bytes, worth hoisting
+// into a package-level cache when lazy mode is on: below this, the extra
+// package-level decl and indirection cost more than the repeated decode
+// they're meant to avoid.
+const defaultLazyMinSize = 256
+
+// fileContext carries the mutable state Obfuscate needs to hoist
+// obfuscated literals into lazily-initialized, package-level caches: the
+// *ast.File new decls get appended to, and a counter for generating
+// identifiers that won't collide with anything else in the package.
+//
+// counter is a pointer shared by every fileContext Obfuscate creates for
+// a given call: the generated _garbleLitN names are package-level, and
+// Go package scope spans every file in the package, so a counter that
+// restarted at 0 per file would emit the same name twice in any package
+// with more than one file that hoists a literal.
+type fileContext struct {
+	opts     *Options
+	file     *ast.File
+	counter  *int
+	usesSync bool
+}
+
+func newFileContext(opts *Options, file *ast.File, counter *int) *fileContext {
+	return &fileContext{opts: opts, file: file, counter: counter}
+}
+
+func (fctx *fileContext) nextID() int {
+	id := *fctx.counter
+	*fctx.counter = id + 1
+	return id
+}
+
+// hoist turns call - a func() T { ... }() immediately-invoked lambda, as
+// produced by lambdaCall - into a call to a package-level, one-shot
+// cached accessor, appending the decl(s) backing that cache to
+// fctx.file. Every repeated reference to the same literal then reuses
+// the cached result instead of re-running the decode. If lazy mode is
+// off or size is below the configured threshold, call is returned
+// unchanged.
+//
+// sliceType must be non-nil when call's result is a slice, and is the
+// slice's type expression (e.g. []byte). A cache holds a single value,
+// and a slice value is just a header pointing at a shared backing array:
+// handing that same header out to every call site would mean a write
+// through any one of them - a completely normal thing to do with a
+// decoded buffer - silently corrupts the literal for every other use for
+// the rest of the program's life. So slice results are cloned on every
+// access instead of returned directly; the decode itself still only runs
+// once. Value types (strings, fixed-size arrays, numbers) are copied by
+// ordinary assignment and can be cached and returned as-is.
+func (fctx *fileContext) hoist(size int, call *ast.CallExpr, sliceType ast.Expr) *ast.CallExpr {
+	if !fctx.opts.lazy || size < fctx.opts.lazyMinSize {
+		return call
+	}
+
+	fn, ok := call.Fun.(*ast.FuncLit)
+	if !ok {
+		return call
+	}
+
+	name := fmt.Sprintf("_garbleLit%d", fctx.nextID())
+	fctx.usesSync = true
+	fctx.appendCacheDecls(name, fn)
+
+	accessor := callExpr(ident(name))
+	if sliceType == nil {
+		return accessor
+	}
+
+	return &ast.CallExpr{
+		Fun:      ident("append"),
+		Args:     []ast.Expr{callExpr(sliceType, ident("nil")), accessor},
+		Ellipsis: 1,
+	}
+}
+
+// appendCacheDecls appends the package-level decl(s) that make name a
+// one-shot cached accessor for fn's result: a single sync.OnceValue var
+// on targets that have it (Go 1.21+), or a hand-rolled sync.Once guarding
+// a cached value behind a same-named accessor func on older targets.
+func (fctx *fileContext) appendCacheDecls(name string, fn *ast.FuncLit) {
+	if fctx.opts.onceValueSupported {
+		fctx.file.Decls = append(fctx.file.Decls, &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{ident(name)},
+				Values: []ast.Expr{callExpr(
+					&ast.SelectorExpr{X: ident("sync"), Sel: ident("OnceValue")},
+					fn,
+				)},
+			}},
+		})
+		return
+	}
+
+	resultType := fn.Type.Results.List[0].Type
+	onceName := name + "Once"
+	valName := name + "Val"
+
+	fctx.file.Decls = append(fctx.file.Decls,
+		&ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{
+				&ast.ValueSpec{
+					Names: []*ast.Ident{ident(onceName)},
+					Type:  &ast.SelectorExpr{X: ident("sync"), Sel: ident("Once")},
+				},
+				&ast.ValueSpec{
+					Names: []*ast.Ident{ident(valName)},
+					Type:  resultType,
+				},
+			},
+		},
+		&ast.FuncDecl{
+			Name: ident(name),
+			Type: &ast.FuncType{Results: fn.Type.Results},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.ExprStmt{X: callExpr(
+					&ast.SelectorExpr{X: ident(onceName), Sel: ident("Do")},
+					&ast.FuncLit{
+						Type: &ast.FuncType{},
+						Body: &ast.BlockStmt{List: []ast.Stmt{
+							&ast.AssignStmt{
+								Lhs: []ast.Expr{ident(valName)},
+								Tok: token.ASSIGN,
+								Rhs: []ast.Expr{&ast.CallExpr{Fun: fn}},
+							},
+						}},
+					},
+				)},
+				returnStmt(ident(valName)),
+			}},
+		},
+	)
+}
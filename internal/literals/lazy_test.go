@@ -0,0 +1,87 @@
+package literals
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+)
+
+func fakeLambdaCall(resultType ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun: &ast.FuncLit{
+			Type: &ast.FuncType{Results: &ast.FieldList{List: []*ast.Field{{Type: resultType}}}},
+			Body: &ast.BlockStmt{},
+		},
+	}
+}
+
+func TestHoistCounterSharedAcrossFiles(t *testing.T) {
+	opts := &Options{lazy: true, lazyMinSize: 1, onceValueSupported: true}
+	counter := 0
+
+	fileA := &ast.File{}
+	fileB := &ast.File{}
+	fctxA := newFileContext(opts, fileA, &counter)
+	fctxB := newFileContext(opts, fileB, &counter)
+
+	fctxA.hoist(10, fakeLambdaCall(ident("string")), nil)
+	fctxB.hoist(10, fakeLambdaCall(ident("string")), nil)
+
+	nameOf := func(f *ast.File) string {
+		decl := f.Decls[0].(*ast.GenDecl)
+		return decl.Specs[0].(*ast.ValueSpec).Names[0].Name
+	}
+
+	nameA, nameB := nameOf(fileA), nameOf(fileB)
+	if nameA == nameB {
+		t.Fatalf("hoist produced the same package-level name %q for two different files", nameA)
+	}
+}
+
+func TestHoistSliceResultIsCloned(t *testing.T) {
+	opts := &Options{lazy: true, lazyMinSize: 1, onceValueSupported: true}
+	counter := 0
+	fctx := newFileContext(opts, &ast.File{}, &counter)
+
+	sliceType := &ast.ArrayType{Elt: ident("byte")}
+	got := fctx.hoist(10, fakeLambdaCall(sliceType), sliceType)
+
+	fn, ok := got.Fun.(*ast.Ident)
+	if !ok || fn.Name != "append" {
+		t.Fatalf("hoist of a slice result = %#v, want a call to append for a defensive copy", got)
+	}
+	if len(got.Args) != 2 || got.Ellipsis == token.NoPos {
+		t.Fatalf("hoist of a slice result didn't build append(dst, src...): %#v", got)
+	}
+}
+
+func TestHoistValueResultIsNotCloned(t *testing.T) {
+	opts := &Options{lazy: true, lazyMinSize: 1, onceValueSupported: true}
+	counter := 0
+	fctx := newFileContext(opts, &ast.File{}, &counter)
+
+	got := fctx.hoist(10, fakeLambdaCall(ident("string")), nil)
+
+	if _, ok := got.Fun.(*ast.Ident); !ok || len(got.Args) != 0 {
+		t.Fatalf("hoist of a value result = %#v, want a bare accessor call", got)
+	}
+}
+
+func TestHoistPreOneTwentyOneFallback(t *testing.T) {
+	opts := &Options{lazy: true, lazyMinSize: 1, onceValueSupported: false}
+	counter := 0
+	file := &ast.File{}
+	fctx := newFileContext(opts, file, &counter)
+
+	fctx.hoist(10, fakeLambdaCall(ident("string")), nil)
+
+	if len(file.Decls) != 2 {
+		t.Fatalf("pre-1.21 fallback produced %d decls, want 2 (vars + accessor func)", len(file.Decls))
+	}
+	if _, ok := file.Decls[0].(*ast.GenDecl); !ok {
+		t.Fatalf("pre-1.21 fallback's first decl = %#v, want *ast.GenDecl", file.Decls[0])
+	}
+	if _, ok := file.Decls[1].(*ast.FuncDecl); !ok {
+		t.Fatalf("pre-1.21 fallback's second decl = %#v, want *ast.FuncDecl", file.Decls[1])
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
-	mathrand "math/rand"
 	"strconv"
 
 	"golang.org/x/tools/go/ast/astutil"
@@ -17,13 +16,10 @@ var (
 	universalFalse = types.Universe.Lookup("false")
 )
 
-func randObfuscator() obfuscator {
-	randPos := mathrand.Intn(len(obfuscators))
-	return obfuscators[randPos]
-}
-
-// Obfuscate replace literals with obfuscated lambda functions
-func Obfuscate(files []*ast.File, info *types.Info, fset *token.FileSet, blacklist map[types.Object]struct{}) []*ast.File {
+// Obfuscate replaces literals with obfuscated lambda functions. opts
+// controls which obfuscator implementations are eligible and which
+// literals are worth obfuscating in the first place; see NewOptions.
+func Obfuscate(opts *Options, files []*ast.File, info *types.Info, fset *token.FileSet, blacklist map[types.Object]struct{}) []*ast.File {
 	pre := func(cursor *astutil.Cursor) bool {
 
 		switch x := cursor.Node().(type) {
@@ -65,138 +61,165 @@ func Obfuscate(files []*ast.File, info *types.Info, fset *token.FileSet, blackli
 		return true
 	}
 
-	post := func(cursor *astutil.Cursor) bool {
-		switch x := cursor.Node().(type) {
-		case *ast.CompositeLit:
-			byteType := types.Universe.Lookup("byte").Type()
+	// counter is shared by every file's fileContext: the _garbleLitN names
+	// it drives are package-level, so a single package with more than one
+	// file that hoists a literal needs them to stay unique across all of
+	// them, not just within one file.
+	counter := 0
 
-			if len(x.Elts) == 0 {
-				return true
-			}
+	for i := range files {
+		usesUnsafe = false
+		fctx := newFileContext(opts, files[i], &counter)
 
-			switch y := info.TypeOf(x.Type).(type) {
-			case *types.Array:
-				if y.Elem() != byteType {
+		post := func(cursor *astutil.Cursor) bool {
+			switch x := cursor.Node().(type) {
+			case *ast.CompositeLit:
+				byteType := types.Universe.Lookup("byte").Type()
+
+				if len(x.Elts) == 0 {
 					return true
 				}
 
-				data := make([]byte, y.Len())
+				switch y := info.TypeOf(x.Type).(type) {
+				case *types.Array:
+					if y.Elem() != byteType {
+						if expr, ok := obfuscateNumericArrayLit(opts, fctx, x, y.Elem(), y.Len()); ok {
+							cursor.Replace(expr)
+						}
+						return true
+					}
+
+					data := make([]byte, y.Len())
 
-				for i, el := range x.Elts {
-					lit, ok := el.(*ast.BasicLit)
-					if !ok {
+					for i, el := range x.Elts {
+						lit, ok := el.(*ast.BasicLit)
+						if !ok {
+							return true
+						}
+
+						value, err := strconv.Atoi(lit.Value)
+						if err != nil {
+							return true
+						}
+
+						data[i] = byte(value)
+					}
+					if !opts.shouldObfuscate(len(data)) {
 						return true
 					}
+					cursor.Replace(obfuscateByteArray(opts, fctx, data, y.Len()))
 
-					value, err := strconv.Atoi(lit.Value)
-					if err != nil {
+				case *types.Slice:
+					if y.Elem() != byteType {
+						if expr, ok := obfuscateNumericArrayLit(opts, fctx, x, y.Elem(), -1); ok {
+							cursor.Replace(expr)
+						}
 						return true
 					}
 
-					data[i] = byte(value)
-				}
-				cursor.Replace(obfuscateByteArray(data, y.Len()))
+					data := make([]byte, 0, len(x.Elts))
 
-			case *types.Slice:
-				if y.Elem() != byteType {
-					return true
-				}
+					for _, el := range x.Elts {
+						lit, ok := el.(*ast.BasicLit)
+						if !ok {
+							return true
+						}
 
-				data := make([]byte, 0, len(x.Elts))
+						value, err := strconv.Atoi(lit.Value)
+						if err != nil {
+							return true
+						}
 
-				for _, el := range x.Elts {
-					lit, ok := el.(*ast.BasicLit)
-					if !ok {
-						return true
+						data = append(data, byte(value))
 					}
-
-					value, err := strconv.Atoi(lit.Value)
-					if err != nil {
+					if !opts.shouldObfuscate(len(data)) {
 						return true
 					}
+					cursor.Replace(obfuscateByteSlice(opts, fctx, data))
 
-					data = append(data, byte(value))
 				}
-				cursor.Replace(obfuscateByteSlice(data))
 
-			}
+			case *ast.BasicLit:
+				switch cursor.Name() {
+				case "Values", "Rhs", "Value", "Args", "X", "Y", "Results":
+				default:
+					return true // we don't want to obfuscate imports etc.
+				}
 
-		case *ast.BasicLit:
-			switch cursor.Name() {
-			case "Values", "Rhs", "Value", "Args", "X", "Y", "Results":
-			default:
-				return true // we don't want to obfuscate imports etc.
-			}
+				switch x.Kind {
+				case token.FLOAT, token.INT:
+					obfuscateNumberLiteral(cursor, info)
+				case token.STRING:
+					typeInfo := info.TypeOf(x)
+					if typeInfo != types.Typ[types.String] && typeInfo != types.Typ[types.UntypedString] {
+						return true
+					}
+					value, err := strconv.Unquote(x.Value)
+					if err != nil {
+						panic(fmt.Sprintf("cannot unquote string: %v", err))
+					}
 
-			switch x.Kind {
-			case token.FLOAT, token.INT:
-				obfuscateNumberLiteral(cursor, info)
-			case token.STRING:
-				typeInfo := info.TypeOf(x)
-				if typeInfo != types.Typ[types.String] && typeInfo != types.Typ[types.UntypedString] {
-					return true
+					if len(value) == 0 {
+						return true
+					}
+					if !opts.shouldObfuscate(len(value)) {
+						return true
+					}
+
+					cursor.Replace(obfuscateString(opts, fctx, value))
 				}
-				value, err := strconv.Unquote(x.Value)
-				if err != nil {
-					panic(fmt.Sprintf("cannot unquote string: %v", err))
+			case *ast.UnaryExpr:
+				switch cursor.Name() {
+				case "Values", "Rhs", "Value", "Args", "X":
+				default:
+					return true // we don't want to obfuscate imports etc.
 				}
 
-				if len(value) == 0 {
+				obfuscateNumberLiteral(cursor, info)
+			case *ast.Ident:
+				obj := info.ObjectOf(x)
+				if obj == nil {
 					return true
 				}
 
-				cursor.Replace(obfuscateString(value))
-			}
-		case *ast.UnaryExpr:
-			switch cursor.Name() {
-			case "Values", "Rhs", "Value", "Args", "X":
-			default:
-				return true // we don't want to obfuscate imports etc.
-			}
-
-			obfuscateNumberLiteral(cursor, info)
-		case *ast.Ident:
-			obj := info.ObjectOf(x)
-			if obj == nil {
-				return true
+				if obj == universalTrue || obj == universalFalse {
+					cursor.Replace(obfuscateBool(x.Name == "true"))
+				}
 			}
 
-			if obj == universalTrue || obj == universalFalse {
-				cursor.Replace(obfuscateBool(x.Name == "true"))
-			}
+			return true
 		}
 
-		return true
-	}
-
-	for i := range files {
-		usesUnsafe = false
 		files[i] = astutil.Apply(files[i], pre, post).(*ast.File)
 		if usesUnsafe {
 			astutil.AddImport(fset, files[i], "unsafe")
 		}
+		if fctx.usesSync {
+			astutil.AddImport(fset, files[i], "sync")
+		}
 	}
 	return files
 }
 
-func obfuscateString(data string) *ast.CallExpr {
-	obfuscator := randObfuscator()
+func obfuscateString(opts *Options, fctx *fileContext, data string) *ast.CallExpr {
+	obfuscator := opts.randObfuscator(len(data))
 	block := obfuscator.obfuscate([]byte(data))
 
 	block.List = append(block.List, returnStmt(callExpr(ident("string"), ident("data"))))
 
-	return lambdaCall(ident("string"), block)
+	return fctx.hoist(len(data), lambdaCall(ident("string"), block), nil)
 }
 
-func obfuscateByteSlice(data []byte) *ast.CallExpr {
-	obfuscator := randObfuscator()
+func obfuscateByteSlice(opts *Options, fctx *fileContext, data []byte) *ast.CallExpr {
+	obfuscator := opts.randObfuscator(len(data))
 	block := obfuscator.obfuscate(data)
 	block.List = append(block.List, returnStmt(ident("data")))
-	return lambdaCall(&ast.ArrayType{Elt: ident("byte")}, block)
+	sliceType := &ast.ArrayType{Elt: ident("byte")}
+	return fctx.hoist(len(data), lambdaCall(sliceType, block), sliceType)
 }
 
-func obfuscateByteArray(data []byte, length int64) *ast.CallExpr {
-	obfuscator := randObfuscator()
+func obfuscateByteArray(opts *Options, fctx *fileContext, data []byte, length int64) *ast.CallExpr {
+	obfuscator := opts.randObfuscator(len(data))
 	block := obfuscator.obfuscate(data)
 
 	arrayType := &ast.ArrayType{
@@ -231,7 +254,7 @@ func obfuscateByteArray(data []byte, length int64) *ast.CallExpr {
 
 	block.List = append(block.List, sliceToArray...)
 
-	return lambdaCall(arrayType, block)
+	return fctx.hoist(len(data), lambdaCall(arrayType, block), nil)
 }
 
 func obfuscateBool(data bool) *ast.BinaryExpr {
@@ -0,0 +1,262 @@
+package literals
+
+import (
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// obfuscators is the full set of known obfuscator implementations.
+// NewOptions' default pool and filterObfuscators both iterate over this,
+// so adding a new obfuscator implementation to the package means adding
+// it here too.
+var obfuscators = []obfuscator{
+	simple{},
+	shuffle{},
+	swap{},
+	split{},
+	flatten{},
+}
+
+// largePayloadThreshold is the size, in bytes, above which Options steers
+// away from obfuscators whose generated AST is large relative to the
+// payload (see obfuscatorHeavy), so a large //go:embed-style byte
+// blob doesn't quietly double or triple the package's compile time.
+const largePayloadThreshold = 32 * 1024
+
+// Options controls which literals get obfuscated and which obfuscator
+// implementations are eligible to do it. A zero Options is not valid;
+// build one with NewOptions.
+type Options struct {
+	// rand drives obfuscator selection. Obfuscate uses one Options per
+	// compilation, so a fixed GARBLE_LITERALS_SEED makes the choice of
+	// obfuscator for each literal reproducible. The obfuscators themselves
+	// do not use it: flatten draws its keys, chunking and state numbers
+	// from the global math/rand source, so the generated code still varies
+	// between builds.
+	rand *mathrand.Rand
+
+	// minSize and maxSize bound which literals are worth obfuscating: too
+	// small and the lambda overhead dominates, too large and the
+	// generated AST can blow up compile times.
+	minSize int
+	maxSize int
+
+	// pool is the allow/deny-filtered set of obfuscators to pick from.
+	pool []obfuscator
+
+	// lazy enables hoisting obfuscated literals into package-level
+	// one-shot caches, so repeated references decode once instead of on
+	// every call. lazyMinSize is the smallest literal worth it.
+	lazy        bool
+	lazyMinSize int
+
+	// onceValueSupported reports whether the target Go version has
+	// sync.OnceValue (added in Go 1.21). When lazy hoisting emits a
+	// cache for a target without it, it falls back to a hand-rolled
+	// sync.Once instead.
+	onceValueSupported bool
+}
+
+const (
+	defaultMinSize = 1
+	defaultMaxSize = 2 << 20 // 2MiB
+)
+
+// NewOptions builds the literal obfuscation options for the current
+// compilation, honoring:
+//
+//   - GARBLE_LITERALS_SEED: integer seed for the PRNG driving obfuscator
+//     selection; keys and other per-obfuscator randomness are not seeded
+//     by it.
+//   - GARBLE_LITERALS_MIN_SIZE, GARBLE_LITERALS_MAX_SIZE: byte bounds on
+//     which literals get obfuscated.
+//   - GARBLE_LITERALS_OBFUSCATORS: a comma-separated allow list of
+//     obfuscator names (or a deny list, if every name is prefixed with
+//     "-"); unset means every known obfuscator is eligible.
+//   - GARBLE_LITERALS_LAZY: if set to a true-ish value, hoists obfuscated
+//     literals at or above GARBLE_LITERALS_LAZY_MIN_SIZE (default
+//     defaultLazyMinSize) into package-level one-shot caches.
+//   - GARBLE_GOVERSION: the target Go version (e.g. "go1.20"), used to
+//     decide whether lazy hoisting can rely on sync.OnceValue (Go 1.21+)
+//     or needs its hand-rolled sync.Once fallback; unset assumes a
+//     toolchain new enough to have sync.OnceValue.
+func NewOptions() *Options {
+	opts := &Options{
+		rand:               mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+		minSize:            defaultMinSize,
+		maxSize:            defaultMaxSize,
+		pool:               obfuscators,
+		lazyMinSize:        defaultLazyMinSize,
+		onceValueSupported: true,
+	}
+
+	if seed := os.Getenv("GARBLE_LITERALS_SEED"); seed != "" {
+		n, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid GARBLE_LITERALS_SEED: %v", err))
+		}
+		opts.rand = mathrand.New(mathrand.NewSource(n))
+	}
+
+	if s := os.Getenv("GARBLE_LITERALS_MIN_SIZE"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			panic(fmt.Sprintf("invalid GARBLE_LITERALS_MIN_SIZE: %v", err))
+		}
+		opts.minSize = n
+	}
+
+	if s := os.Getenv("GARBLE_LITERALS_MAX_SIZE"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			panic(fmt.Sprintf("invalid GARBLE_LITERALS_MAX_SIZE: %v", err))
+		}
+		opts.maxSize = n
+	}
+
+	if list := os.Getenv("GARBLE_LITERALS_OBFUSCATORS"); list != "" {
+		opts.pool = filterObfuscators(list)
+	}
+
+	if s := os.Getenv("GARBLE_LITERALS_LAZY"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			panic(fmt.Sprintf("invalid GARBLE_LITERALS_LAZY: %v", err))
+		}
+		opts.lazy = b
+	}
+
+	if s := os.Getenv("GARBLE_LITERALS_LAZY_MIN_SIZE"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			panic(fmt.Sprintf("invalid GARBLE_LITERALS_LAZY_MIN_SIZE: %v", err))
+		}
+		opts.lazyMinSize = n
+	}
+
+	if v := os.Getenv("GARBLE_GOVERSION"); v != "" {
+		opts.onceValueSupported = goVersionAtLeast(v, 1, 21)
+	}
+
+	return opts
+}
+
+// goVersionAtLeast reports whether goVersion - in either "go1.21.0" or
+// "1.21" form, as accepted by GARBLE_GOVERSION - is at least major.minor.
+// An unparseable version is treated as not meeting the requirement, so
+// callers fall back to the more compatible behavior.
+func goVersionAtLeast(goVersion string, major, minor int) bool {
+	v := strings.TrimPrefix(goVersion, "go")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	gotMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+
+	if gotMajor != major {
+		return gotMajor > major
+	}
+	return gotMinor >= minor
+}
+
+// filterObfuscators applies the GARBLE_LITERALS_OBFUSCATORS allow/deny
+// list to the default obfuscator pool. A leading "-" on the first name
+// turns the whole list into a deny list; the two forms cannot be mixed.
+func filterObfuscators(list string) []obfuscator {
+	names := strings.Split(list, ",")
+	deny := strings.HasPrefix(strings.TrimSpace(names[0]), "-")
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "-") != deny {
+			panic(fmt.Sprintf("GARBLE_LITERALS_OBFUSCATORS mixes allow and deny entries: %q", list))
+		}
+		wanted[strings.TrimPrefix(name, "-")] = true
+	}
+
+	var filtered []obfuscator
+	for _, obf := range obfuscators {
+		if wanted[obfuscatorName(obf)] != deny {
+			filtered = append(filtered, obf)
+		}
+	}
+	if len(filtered) == 0 {
+		panic("GARBLE_LITERALS_OBFUSCATORS filtered out every known obfuscator")
+	}
+	return filtered
+}
+
+// shouldObfuscate reports whether a literal of the given size falls
+// within the configured [minSize, maxSize] window.
+func (o *Options) shouldObfuscate(size int) bool {
+	return size >= o.minSize && size <= o.maxSize
+}
+
+// randObfuscator picks an obfuscator for a payload of the given size. For
+// payloads over largePayloadThreshold it prefers obfuscators that don't
+// blow up the generated AST, falling back to the full pool only if that
+// leaves nothing to pick from.
+func (o *Options) randObfuscator(size int) obfuscator {
+	pool := o.pool
+	if size > largePayloadThreshold {
+		if light := excludeHeavy(pool); len(light) > 0 {
+			pool = light
+		}
+	}
+	return pool[o.rand.Intn(len(pool))]
+}
+
+func excludeHeavy(pool []obfuscator) []obfuscator {
+	var light []obfuscator
+	for _, obf := range pool {
+		if !obfuscatorHeavy(obf) {
+			light = append(light, obf)
+		}
+	}
+	return light
+}
+
+// obfuscatorName returns the stable name used to refer to an obfuscator
+// implementation in GARBLE_LITERALS_OBFUSCATORS.
+func obfuscatorName(obf obfuscator) string {
+	switch obf.(type) {
+	case simple:
+		return "simple"
+	case shuffle:
+		return "shuffle"
+	case swap:
+		return "swap"
+	case split:
+		return "split"
+	case flatten:
+		return "flatten"
+	default:
+		return fmt.Sprintf("%T", obf)
+	}
+}
+
+// obfuscatorHeavy reports whether an obfuscator's generated AST is large
+// relative to its input (flatten, for one, emits a separately obfuscated
+// key expression for every payload byte), making it a poor fit for very
+// large payloads where compile time matters.
+func obfuscatorHeavy(obf obfuscator) bool {
+	switch obf.(type) {
+	case split, flatten:
+		return true
+	default:
+		return false
+	}
+}
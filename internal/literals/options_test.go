@@ -0,0 +1,133 @@
+package literals
+
+import (
+	mathrand "math/rand"
+	"testing"
+)
+
+func TestFilterObfuscatorsAllowFlatten(t *testing.T) {
+	pool := filterObfuscators("flatten")
+	if len(pool) != 1 || obfuscatorName(pool[0]) != "flatten" {
+		t.Fatalf("filterObfuscators(%q) = %v, want just flatten", "flatten", pool)
+	}
+}
+
+func TestFilterObfuscatorsDenyFlatten(t *testing.T) {
+	pool := filterObfuscators("-flatten")
+	if len(pool) != len(obfuscators)-1 {
+		t.Fatalf("filterObfuscators(%q) = %v, want every obfuscator but flatten", "-flatten", pool)
+	}
+	for _, obf := range pool {
+		if obfuscatorName(obf) == "flatten" {
+			t.Fatalf("filterObfuscators(%q) kept flatten", "-flatten")
+		}
+	}
+}
+
+func TestGoVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"go1.21", true},
+		{"go1.21.0", true},
+		{"go1.22", true},
+		{"1.21", true},
+		{"go1.20", false},
+		{"go1.9", false},
+		{"not-a-version", false},
+	}
+	for _, tt := range tests {
+		if got := goVersionAtLeast(tt.version, 1, 21); got != tt.want {
+			t.Errorf("goVersionAtLeast(%q, 1, 21) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestFilterObfuscatorsUnknownNamePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a name that matches no obfuscator")
+		}
+	}()
+	filterObfuscators("does-not-exist")
+}
+
+// TestDefaultPoolIncludesEveryObfuscator guards against a new obfuscator
+// implementation being wired into obfuscatorName/obfuscatorHeavy but
+// never added to obfuscators itself, which leaves it unreachable through
+// either the default pool or GARBLE_LITERALS_OBFUSCATORS (as happened
+// with flatten for several commits in this package's history).
+func TestDefaultPoolIncludesEveryObfuscator(t *testing.T) {
+	for _, name := range []string{"simple", "shuffle", "swap", "split", "flatten"} {
+		found := false
+		for _, obf := range obfuscators {
+			if obfuscatorName(obf) == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("obfuscators is missing %q", name)
+		}
+	}
+
+	found := false
+	for _, obf := range NewOptions().pool {
+		if obfuscatorName(obf) == "flatten" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("NewOptions().pool doesn't include flatten")
+	}
+}
+
+func TestFilterObfuscatorsMixedAllowDenyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a list mixing allow and deny entries")
+		}
+	}()
+	filterObfuscators("flatten,-simple")
+}
+
+func TestRandObfuscatorExcludesHeavyForLargePayloads(t *testing.T) {
+	o := &Options{rand: mathrand.New(mathrand.NewSource(1)), pool: obfuscators}
+
+	for i := 0; i < 50; i++ {
+		obf := o.randObfuscator(largePayloadThreshold + 1)
+		if obfuscatorHeavy(obf) {
+			t.Fatalf("randObfuscator picked heavy obfuscator %s for a payload over largePayloadThreshold", obfuscatorName(obf))
+		}
+	}
+}
+
+func TestRandObfuscatorFallsBackToFullPoolIfAllHeavy(t *testing.T) {
+	o := &Options{rand: mathrand.New(mathrand.NewSource(1)), pool: []obfuscator{split{}, flatten{}}}
+
+	obf := o.randObfuscator(largePayloadThreshold + 1)
+	if !obfuscatorHeavy(obf) {
+		t.Fatalf("randObfuscator picked non-heavy obfuscator %s when the whole pool is heavy", obfuscatorName(obf))
+	}
+}
+
+func TestShouldObfuscateBounds(t *testing.T) {
+	o := &Options{minSize: 4, maxSize: 8}
+
+	tests := []struct {
+		size int
+		want bool
+	}{
+		{3, false},
+		{4, true},
+		{8, true},
+		{9, false},
+	}
+	for _, tt := range tests {
+		if got := o.shouldObfuscate(tt.size); got != tt.want {
+			t.Errorf("shouldObfuscate(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}